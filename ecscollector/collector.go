@@ -18,13 +18,24 @@ package ecscollector
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/prometheus-community/ecs_exporter/ecsmetadata"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// staleAfterFactor is how many refresh intervals may pass without a
+// successful refresh before the cached snapshot is considered stale for
+// readiness purposes.
+const staleAfterFactor = 3
+
+// initialRefreshTimeout bounds the cache population done by NewCollector
+// before it returns, so a slow or unreachable metadata server delays
+// startup by at most this long instead of hanging it indefinitely.
+const initialRefreshTimeout = 5 * time.Second
+
 // ECS cpu_stats are from upstream docker/moby. These values are in nanoseconds.
 // https://github.com/moby/moby/blob/49f021ebf00a76d74f5ce158244083e2dfba26fb/api/types/stats.go#L18-L40
 const (
@@ -32,6 +43,10 @@ const (
 	timeLayout = "2006-01-02T15:04:05.999999999Z"
 	cpuIn1Vcpu = 1024
 	bytesInMiB = 1024 * 1024
+	// windowsCPUUsageUnit converts UsageInKernelmode/UsageInUsermode to
+	// nanoseconds: on Windows those fields are reported in 100's of
+	// nanoseconds, unlike their Linux nanosecond-denominated counterparts.
+	windowsCPUUsageUnit = 100
 )
 
 
@@ -53,18 +68,31 @@ var (
 	networkTxPacketsDesc *prometheus.Desc
 	networkTxDroppedDesc *prometheus.Desc
 	networkTxErrorsDesc *prometheus.Desc
+	networkRxBytesPerSecDesc *prometheus.Desc
+	networkTxBytesPerSecDesc *prometheus.Desc
+	clockErrorBoundDesc *prometheus.Desc
+	clockSynchronizedDesc *prometheus.Desc
+	osFamilyDesc *prometheus.Desc
+	upDesc *prometheus.Desc
 
 	labels []string
 	svcLabels []string
 	metadataLabels []string
 	cpuLabels []string
 	networkLabels []string
+	osFamilyLabels []string
 
 )
 
 // NewCollector returns a new Collector that queries ECS metadata server
 // for ECS task and container metrics.
-func NewCollector(client *ecsmetadata.Client, customLabels map[string]string) prometheus.Collector {
+//
+// A background goroutine refreshes the cached task metadata and stats every
+// refreshInterval; scrapes are served from that cache rather than hitting
+// the metadata server directly, so that concurrent or frequent scrapes
+// don't hammer it. If refreshInterval is zero or negative, the cache is
+// populated once and never refreshed.
+func NewCollector(client ecsmetadata.MetadataSource, customLabels map[string]string, refreshInterval time.Duration) *Collector {
 	metadataLabels = []string{
 		"cluster",
 		"task_arn",
@@ -77,6 +105,7 @@ func NewCollector(client *ecsmetadata.Client, customLabels map[string]string) pr
 		"availability_zone",
 		"launch_type",
 		"task_id",
+		"platform",
 	}
 	svcLabels = []string{
 		"task_arn",
@@ -100,14 +129,12 @@ func NewCollector(client *ecsmetadata.Client, customLabels map[string]string) pr
 	metadataLabels = append(metadataLabels, customLabelKeys...)
 	labels = append(labels, customLabelKeys...)
 	svcLabels = append(svcLabels, customLabelKeys...)
-	networkLabels = append(
-		labels,
-		"device",
-	)
-	cpuLabels = append(
-		labels,
-		"cpu",
-	)
+	// Each of these needs its own backing array: appending onto labels
+	// directly would alias its spare capacity, so whichever of these ran
+	// last would silently overwrite the others' extra label.
+	networkLabels = append(append([]string{}, labels...), "device")
+	cpuLabels = append(append([]string{}, labels...), "cpu")
+	osFamilyLabels = append(append([]string{}, labels...), "os_family")
 
 	// Initialize all the metric descriptors.
 
@@ -196,16 +223,154 @@ func NewCollector(client *ecsmetadata.Client, customLabels map[string]string) pr
 		"Network errors in transmit.",
 		networkLabels, nil)
 
+	networkRxBytesPerSecDesc = prometheus.NewDesc(
+		"ecs_network_receive_bytes_per_second",
+		"Instantaneous network receive rate in bytes per second.",
+		labels, nil)
+
+	networkTxBytesPerSecDesc = prometheus.NewDesc(
+		"ecs_network_transmit_bytes_per_second",
+		"Instantaneous network transmit rate in bytes per second.",
+		labels, nil)
+
+	clockErrorBoundDesc = prometheus.NewDesc(
+		"ecs_container_clock_error_bound_seconds",
+		"Clock error bound reported for the container, in seconds.",
+		labels, nil)
+
+	clockSynchronizedDesc = prometheus.NewDesc(
+		"ecs_container_clock_synchronized",
+		"Whether the container clock is synchronized (1) or not (0).",
+		labels, nil)
+
+	osFamilyDesc = prometheus.NewDesc(
+		"ecs_container_os_family",
+		"Container OS family, e.g. linux or windows.",
+		osFamilyLabels, nil)
+
+	upDesc = prometheus.NewDesc(
+		"ecs_exporter_up",
+		"Whether the exporter's cached ECS metadata is fresh (1) or stale/unavailable (0).",
+		nil, nil)
+
+	c := &Collector{
+		client:            client,
+		customLabelValues: customLabelValues,
+		staleAfter:        refreshInterval * staleAfterFactor,
+		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ecs_metadata_scrape_errors_total",
+			Help: "Total number of errors scraping the ECS metadata endpoints, by endpoint.",
+		}, []string{"endpoint"}),
+		lastSuccessTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ecs_metadata_last_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful refresh of the cached ECS metadata.",
+		}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "ecs_metadata_request_duration_seconds",
+			Help: "Duration of requests to the ECS metadata endpoints, by endpoint.",
+		}, []string{"endpoint"}),
+	}
+
+	// Initialize the known endpoint label values to zero so the
+	// ecs_metadata_scrape_errors_total series exist from the start rather
+	// than only appearing after the first error on each endpoint. Besides
+	// the usual "missing series" problem this avoids for dashboards, it
+	// also lets the OTLP exporter discover these series on its first
+	// gather instead of never exporting them at all.
+	c.scrapeErrors.WithLabelValues("task")
+	c.scrapeErrors.WithLabelValues("stats")
+
+	// Populate the cache before returning so the first scrape has data to
+	// serve, then keep it warm in the background. Bounded by
+	// initialRefreshTimeout so a slow or unreachable metadata server can't
+	// block the process from starting and serving /-/healthy.
+	initialCtx, cancel := context.WithTimeout(context.Background(), initialRefreshTimeout)
+	c.refresh(initialCtx)
+	cancel()
+
+	if refreshInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(refreshInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				c.refresh(context.Background())
+			}
+		}()
+	}
 
-	return &collector{client: client, customLabelValues: customLabelValues}
+	return c
 }
 
-type collector struct {
-	client *ecsmetadata.Client
+// Collector is a prometheus.Collector that queries the ECS metadata server
+// for ECS task and container metrics, serving scrapes from a periodically
+// refreshed cache.
+type Collector struct {
+	client ecsmetadata.MetadataSource
 	customLabelValues []string
+	// staleAfter is how long the cache may go without a successful refresh
+	// before Ready reports false. Zero means the cache never goes stale
+	// once populated (used when refreshInterval is disabled).
+	staleAfter time.Duration
+
+	mu             sync.RWMutex
+	cachedMetadata *ecsmetadata.TaskMetadata
+	cachedStats    map[string]*ecsmetadata.ContainerStats
+	lastSuccess    time.Time
+
+	scrapeErrors         *prometheus.CounterVec
+	lastSuccessTimestamp prometheus.Gauge
+	requestDuration      *prometheus.HistogramVec
 }
 
-func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+// Ready reports whether the cached ECS metadata was refreshed recently
+// enough to be trusted, for use by a readiness probe.
+func (c *Collector) Ready() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lastSuccess.IsZero() {
+		return false
+	}
+	if c.staleAfter <= 0 {
+		return true
+	}
+	return time.Since(c.lastSuccess) < c.staleAfter
+}
+
+// refresh fetches the current task metadata and stats and, on success,
+// stores them as the cached snapshot served by Collect.
+func (c *Collector) refresh(ctx context.Context) {
+	start := time.Now()
+	metadata, err := c.client.RetrieveTaskMetadata(ctx)
+	c.requestDuration.WithLabelValues("task").Observe(time.Since(start).Seconds())
+	if err != nil {
+		c.scrapeErrors.WithLabelValues("task").Inc()
+		slog.Error("failed to retrieve task metadata", "error", err)
+		return
+	}
+
+	start = time.Now()
+	stats, err := c.client.RetrieveTaskStats(ctx)
+	c.requestDuration.WithLabelValues("stats").Observe(time.Since(start).Seconds())
+	if err != nil {
+		c.scrapeErrors.WithLabelValues("stats").Inc()
+		slog.Error("failed to retrieve task stats", "error", err)
+		return
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	c.cachedMetadata = metadata
+	c.cachedStats = stats
+	c.lastSuccess = now
+	c.mu.Unlock()
+
+	c.lastSuccessTimestamp.Set(float64(now.Unix()))
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metadataDesc
+	ch <- svcCpuLimitDesc
+	ch <- svcMemLimitDesc
 	ch <- cpuTotalDesc
 	ch <- cpuUtilizedDesc
 	ch <- memoryUtilizedDesc
@@ -220,13 +385,35 @@ func (c *collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- networkTxPacketsDesc
 	ch <- networkTxDroppedDesc
 	ch <- networkTxErrorsDesc
+	ch <- networkRxBytesPerSecDesc
+	ch <- networkTxBytesPerSecDesc
+	ch <- clockErrorBoundDesc
+	ch <- clockSynchronizedDesc
+	ch <- osFamilyDesc
+	ch <- upDesc
+	c.scrapeErrors.Describe(ch)
+	c.lastSuccessTimestamp.Describe(ch)
+	c.requestDuration.Describe(ch)
 }
 
-func (c *collector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.Background()
-	metadata, err := c.client.RetrieveTaskMetadata(ctx)
-	if err != nil {
-		log.Printf("Failed to retrieve metadata: %v", err)
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.scrapeErrors.Collect(ch)
+	c.lastSuccessTimestamp.Collect(ch)
+	c.requestDuration.Collect(ch)
+
+	up := 0.0
+	if c.Ready() {
+		up = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up)
+
+	c.mu.RLock()
+	metadata := c.cachedMetadata
+	stats := c.cachedStats
+	c.mu.RUnlock()
+
+	if metadata == nil {
+		slog.Warn("no cached ECS metadata available yet")
 		return
 	}
 
@@ -242,6 +429,7 @@ func (c *collector) Collect(ch chan<- prometheus.Metric) {
 		metadata.AvailabilityZone,
 		metadata.LaunchType,
 		metadata.TaskID,
+		metadata.OSFamily,
 	}
 	metadataLableVals = append(metadataLableVals, c.customLabelValues...)
 
@@ -273,16 +461,10 @@ func (c *collector) Collect(ch chan<- prometheus.Metric) {
 		svcLableVals...,
 	)
 
-	stats, err := c.client.RetrieveTaskStats(ctx)
-	if err != nil {
-		log.Printf("Failed to retrieve container stats: %v", err)
-		return
-	}
-
 	for _, container := range metadata.Containers {
 		s := stats[container.DockerID]
 		if s == nil {
-			log.Printf("Couldn't find container with ID %q in stats", container.DockerID)
+			slog.Warn("container missing from stats", "docker_id", container.DockerID)
 			continue
 		}
 
@@ -293,8 +475,30 @@ func (c *collector) Collect(ch chan<- prometheus.Metric) {
 		}
 		labelVals = append(labelVals, c.customLabelValues...)
 
+		// Windows/Hyper-V tasks report no per-CPU breakdown and populate
+		// UsageInKernelmode/UsageInUsermode instead of TotalUsage.
+		isWindows := len(s.CPUStats.CPUUsage.PercpuUsage) == 0 && s.CPUStats.CPUUsage.UsageInKernelmode > 0
+
+		osFamily := "linux"
+		if isWindows {
+			osFamily = "windows"
+		}
+		ch <- prometheus.MustNewConstMetric(
+			osFamilyDesc,
+			prometheus.GaugeValue,
+			1.0,
+			append(labelVals, osFamily)...,
+		)
+
 		// Calculate CPU usage percentage
-		cpu_delta := s.CPUStats.CPUUsage.TotalUsage - s.PreCPUStats.CPUUsage.TotalUsage
+		var cpu_delta uint64
+		if isWindows {
+			curUsage := s.CPUStats.CPUUsage.UsageInKernelmode + s.CPUStats.CPUUsage.UsageInUsermode
+			preUsage := s.PreCPUStats.CPUUsage.UsageInKernelmode + s.PreCPUStats.CPUUsage.UsageInUsermode
+			cpu_delta = (curUsage - preUsage) * windowsCPUUsageUnit
+		} else {
+			cpu_delta = s.CPUStats.CPUUsage.TotalUsage - s.PreCPUStats.CPUUsage.TotalUsage
+		}
 		// system_delta := s.CPUStats.SystemUsage - s.PreCPUStats.SystemUsage
 
 		parsedReadTime, _ := time.Parse(timeLayout, s.Read)
@@ -331,6 +535,14 @@ func (c *collector) Collect(ch chan<- prometheus.Metric) {
 				memoryUtilizedInMegaBytes,
 				labelVals...,
 			)
+		} else if isWindows && s.MemoryStats.PrivateWorkingSet > 0 {
+			memoryUtilizedInMegaBytes := float64(s.MemoryStats.PrivateWorkingSet) / bytesInMiB
+			ch <- prometheus.MustNewConstMetric(
+				memoryUtilizedDesc,
+				prometheus.GaugeValue,
+				memoryUtilizedInMegaBytes,
+				labelVals...,
+			)
 		}
 
 		for desc, value := range map[*prometheus.Desc]float64{
@@ -368,5 +580,40 @@ func (c *collector) Collect(ch chan<- prometheus.Metric) {
 				)
 			}
 		}
+
+		ch <- prometheus.MustNewConstMetric(
+			networkRxBytesPerSecDesc,
+			prometheus.GaugeValue,
+			s.NetworkRateStats.RxBytesPerSec,
+			labelVals...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			networkTxBytesPerSecDesc,
+			prometheus.GaugeValue,
+			s.NetworkRateStats.TxBytesPerSec,
+			labelVals...,
+		)
+
+		// Clock drift is reported per-reference-clock; use the most recent
+		// reading for the container-level gauges.
+		if n := len(container.ClockDrift); n > 0 {
+			drift := container.ClockDrift[n-1]
+			synchronized := 0.0
+			if drift.ClockSynchronizationStatus == "SYNCHRONIZED" {
+				synchronized = 1.0
+			}
+			ch <- prometheus.MustNewConstMetric(
+				clockErrorBoundDesc,
+				prometheus.GaugeValue,
+				drift.ClockErrorBound,
+				labelVals...,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				clockSynchronizedDesc,
+				prometheus.GaugeValue,
+				synchronized,
+				labelVals...,
+			)
+		}
 	}
 }
@@ -0,0 +1,115 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecscollector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus-community/ecs_exporter/ecsmetadata"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// collectedMetrics are the metric families asserted against the golden
+// files below. Per-CPU and per-interface metrics are exercised indirectly
+// (Collect would panic if they mishandled the fixtures) but are omitted
+// here since their cardinality varies with the fixture's CPU/interface
+// count.
+var collectedMetrics = []string{
+	"ecs_metadata_info",
+	"ecs_cpu_utilized",
+	"ecs_memory_utilized_mega_bytes",
+	"ecs_container_os_family",
+	"ecs_container_clock_error_bound_seconds",
+	"ecs_container_clock_synchronized",
+	"ecs_network_receive_bytes_per_second",
+	"ecs_network_transmit_bytes_per_second",
+}
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		name         string
+		fixture      string
+		golden       string
+		customLabels map[string]string
+		metrics      []string
+	}{
+		{name: "Fargate Linux", fixture: "fargate_linux", golden: "fargate_linux.golden", metrics: collectedMetrics},
+		{name: "EC2 Linux", fixture: "ec2_linux", golden: "ec2_linux.golden", metrics: collectedMetrics},
+		{name: "Fargate Windows", fixture: "fargate_windows", golden: "fargate_windows.golden", metrics: collectedMetrics},
+		{
+			// Regression test: networkLabels, cpuLabels, and osFamilyLabels
+			// used to be built by appending onto the same labels backing
+			// array, so configuring a custom label made the last append
+			// win and the others silently export their value (e.g. the
+			// network device) under the wrong label name.
+			name:         "Fargate Linux with custom labels",
+			fixture:      "fargate_linux",
+			golden:       "fargate_linux_custom_labels.golden",
+			customLabels: map[string]string{"env": "prod"},
+			metrics: append(append([]string{}, collectedMetrics...),
+				"ecs_cpu_seconds_total",
+				"ecs_network_receive_bytes_total",
+				"ecs_network_transmit_bytes_total",
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := ecsmetadata.NewFileClient(
+				filepath.Join("testdata", tc.fixture, "task.json"),
+				filepath.Join("testdata", tc.fixture, "stats.json"),
+			)
+			collector := NewCollector(client, tc.customLabels, 0)
+
+			golden, err := os.Open(filepath.Join("testdata", tc.golden))
+			if err != nil {
+				t.Fatalf("opening golden file: %v", err)
+			}
+			defer golden.Close()
+
+			if err := testutil.CollectAndCompare(collector, golden, tc.metrics...); err != nil {
+				t.Errorf("unexpected collected metrics:\n%v", err)
+			}
+		})
+	}
+}
+
+// TestReadyReflectsRefreshOutcome uses ecsmetadata.FakeClient, rather than
+// FileClient, specifically to simulate a metadata-server failure: unlike
+// FileClient it can return an error from RetrieveTaskMetadata without a
+// fixture file, exercising the path FileClient-backed tests never reach.
+func TestReadyReflectsRefreshOutcome(t *testing.T) {
+	client := &ecsmetadata.FakeClient{
+		TaskMetadataErr: errors.New("metadata server unreachable"),
+	}
+	collector := NewCollector(client, nil, 0)
+
+	if collector.Ready() {
+		t.Error("Ready() = true, want false after a failed refresh")
+	}
+
+	client.TaskMetadataErr = nil
+	client.TaskMetadata = &ecsmetadata.TaskMetadata{TaskARN: "arn:aws:ecs:us-east-1:012345678910:task/default/abc123"}
+	client.TaskStats = map[string]*ecsmetadata.ContainerStats{}
+	collector.refresh(context.Background())
+
+	if !collector.Ready() {
+		t.Error("Ready() = false, want true after a successful refresh")
+	}
+}
@@ -28,6 +28,16 @@ import (
 	dockertypes "github.com/docker/docker/api/types"
 )
 
+// MetadataSource is the data ecscollector needs from a task's metadata
+// endpoint. Client is the production implementation; FileClient and
+// FakeClient back it with static data for local development and tests.
+type MetadataSource interface {
+	RetrieveTaskMetadata(ctx context.Context) (*TaskMetadata, error)
+	RetrieveTaskStats(ctx context.Context) (map[string]*ContainerStats, error)
+}
+
+var _ MetadataSource = (*Client)(nil)
+
 type Client struct {
 	// HTTClient is the client to use when making HTTP requests when set.
 	HTTPClient *http.Client
@@ -69,9 +79,42 @@ func (c *Client) RetrieveTaskMetadata(ctx context.Context) (*TaskMetadata, error
 	var out TaskMetadata
 	err := c.request(ctx, c.endpoint+"/task", &out)
 	out.SetTaskID()
+	out.SetPlatform()
+	return &out, err
+}
+
+// RetrieveContainerMetadata returns metadata for the container making the
+// request, as opposed to RetrieveTaskMetadata which returns metadata for
+// every container in the task.
+func (c *Client) RetrieveContainerMetadata(ctx context.Context) (*ContainerMetadata, error) {
+	var out ContainerMetadata
+	err := c.request(ctx, c.endpoint, &out)
 	return &out, err
 }
 
+// RetrieveContainerStats returns the docker stats for the container making
+// the request, as opposed to RetrieveTaskStats which returns stats for
+// every container in the task.
+func (c *Client) RetrieveContainerStats(ctx context.Context) (*ContainerStats, error) {
+	var out ContainerStats
+	err := c.request(ctx, c.endpoint+"/stats", &out)
+	return &out, err
+}
+
+// RetrievePerContainerStats returns the docker stats for a single container
+// in the task, identified by its docker ID.
+func (c *Client) RetrievePerContainerStats(ctx context.Context, dockerID string) (*ContainerStats, error) {
+	stats, err := c.RetrieveTaskStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := stats[dockerID]
+	if !ok {
+		return nil, fmt.Errorf("no stats found for container %q", dockerID)
+	}
+	return s, nil
+}
+
 func (c *Client) request(ctx context.Context, uri string, out interface{}) error {
 	req, err := http.NewRequest("GET", uri, nil)
 	if err != nil {
@@ -125,6 +168,20 @@ func (t *TaskMetadata) SetTaskID() {
     t.TaskID = parts[len(parts)-1]
 }
 
+// SetPlatform derives OSFamily from the "ecs.os-type" task attribute
+// (present on v4 responses), falling back to "linux" when no attribute is
+// reported.
+func (t *TaskMetadata) SetPlatform() {
+	for _, attr := range t.Attributes {
+		if attr.Name == "ecs.os-type" {
+			t.OSFamily = attr.Value
+		}
+	}
+	if t.OSFamily == "" {
+		t.OSFamily = "linux"
+	}
+}
+
 
 type TaskMetadataLimits struct {
 	CPU    float64 `json:"CPU"`
@@ -144,37 +201,50 @@ type TaskMetadata struct {
 	PullStoppedAt    string             `json:"PullStoppedAt"`
 	AvailabilityZone string             `json:"AvailabilityZone"`
 	LaunchType       string             `json:"LaunchType"`
-	Containers       []struct {
-		DockerID      string            `json:"DockerId"`
-		Name          string            `json:"Name"`
-		DockerName    string            `json:"DockerName"`
-		Image         string            `json:"Image"`
-		ImageID       string            `json:"ImageID"`
-		Labels        map[string]string `json:"Labels"`
-		DesiredStatus string            `json:"DesiredStatus"`
-		KnownStatus   string            `json:"KnownStatus"`
-		CreatedAt     string            `json:"CreatedAt"`
-		StartedAt     string            `json:"StartedAt"`
-		Type          string            `json:"Type"`
-		Networks      []struct {
-			NetworkMode              string   `json:"NetworkMode"`
-			IPv4Addresses            []string `json:"IPv4Addresses"`
-			IPv6Addresses            []string `json:"IPv6Addresses"`
-			AttachmentIndex          float64  `json:"AttachmentIndex"`
-			MACAddress               string   `json:"MACAddress"`
-			IPv4SubnetCIDRBlock      string   `json:"IPv4SubnetCIDRBlock"`
-			IPv6SubnetCIDRBlock      string   `json:"IPv6SubnetCIDRBlock"`
-			DomainNameServers        []string `json:"DomainNameServers"`
-			DomainNameSearchList     []string `json:"DomainNameSearchList"`
-			PrivateDNSName           string   `json:"PrivateDNSName"`
-			SubnetGatewayIpv4Address string   `json:"SubnetGatewayIpv4Address"`
-		} `json:"Networks"`
-		ClockDrift []struct {
-			ClockErrorBound            float64 `json:"ClockErrorBound"`
-			ReferenceTimestamp         string  `json:"ReferenceTimestamp"`
-			ClockSynchronizationStatus string  `json:"ClockSynchronizationStatus"`
-		} `json:"ClockDrift"`
-		ContainerARN string `json:"ContainerARN"`
-		LogDriver    string `json:"LogDriver"`
-	} `json:"Containers"`
+	Containers       []ContainerMetadata `json:"Containers"`
+	Attributes       []struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	} `json:"Attributes"`
+
+	// OSFamily is derived by SetPlatform, not unmarshalled directly from
+	// the metadata response.
+	OSFamily string
+}
+
+// ContainerMetadata describes a single container, either as an element of
+// TaskMetadata.Containers or as returned directly by
+// Client.RetrieveContainerMetadata for the calling container.
+type ContainerMetadata struct {
+	DockerID      string            `json:"DockerId"`
+	Name          string            `json:"Name"`
+	DockerName    string            `json:"DockerName"`
+	Image         string            `json:"Image"`
+	ImageID       string            `json:"ImageID"`
+	Labels        map[string]string `json:"Labels"`
+	DesiredStatus string            `json:"DesiredStatus"`
+	KnownStatus   string            `json:"KnownStatus"`
+	CreatedAt     string            `json:"CreatedAt"`
+	StartedAt     string            `json:"StartedAt"`
+	Type          string            `json:"Type"`
+	Networks      []struct {
+		NetworkMode              string   `json:"NetworkMode"`
+		IPv4Addresses            []string `json:"IPv4Addresses"`
+		IPv6Addresses            []string `json:"IPv6Addresses"`
+		AttachmentIndex          float64  `json:"AttachmentIndex"`
+		MACAddress               string   `json:"MACAddress"`
+		IPv4SubnetCIDRBlock      string   `json:"IPv4SubnetCIDRBlock"`
+		IPv6SubnetCIDRBlock      string   `json:"IPv6SubnetCIDRBlock"`
+		DomainNameServers        []string `json:"DomainNameServers"`
+		DomainNameSearchList     []string `json:"DomainNameSearchList"`
+		PrivateDNSName           string   `json:"PrivateDNSName"`
+		SubnetGatewayIpv4Address string   `json:"SubnetGatewayIpv4Address"`
+	} `json:"Networks"`
+	ClockDrift []struct {
+		ClockErrorBound            float64 `json:"ClockErrorBound"`
+		ReferenceTimestamp         string  `json:"ReferenceTimestamp"`
+		ClockSynchronizationStatus string  `json:"ClockSynchronizationStatus"`
+	} `json:"ClockDrift"`
+	ContainerARN string `json:"ContainerARN"`
+	LogDriver    string `json:"LogDriver"`
 }
@@ -0,0 +1,37 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecsmetadata
+
+import "context"
+
+var _ MetadataSource = (*FakeClient)(nil)
+
+// FakeClient is a MetadataSource backed entirely by in-memory values. It is
+// intended for unit tests of packages that depend on MetadataSource, such
+// as ecscollector.
+type FakeClient struct {
+	TaskMetadata *TaskMetadata
+	TaskStats    map[string]*ContainerStats
+
+	TaskMetadataErr error
+	TaskStatsErr    error
+}
+
+func (f *FakeClient) RetrieveTaskMetadata(ctx context.Context) (*TaskMetadata, error) {
+	return f.TaskMetadata, f.TaskMetadataErr
+}
+
+func (f *FakeClient) RetrieveTaskStats(ctx context.Context) (map[string]*ContainerStats, error) {
+	return f.TaskStats, f.TaskStatsErr
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecsmetadata
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+)
+
+var _ MetadataSource = (*FileClient)(nil)
+
+// FileClient is a MetadataSource that reads task metadata and task stats
+// from local JSON files instead of querying the ECS metadata server. It is
+// intended for local development and testing, where no metadata endpoint
+// is available.
+type FileClient struct {
+	metadataFile string
+	statsFile    string
+}
+
+// NewFileClient returns a new FileClient reading task metadata from
+// metadataFile and task stats from statsFile. Both files are expected to
+// contain the same JSON shapes returned by the "/task" and "/task/stats"
+// metadata endpoints, respectively.
+func NewFileClient(metadataFile, statsFile string) *FileClient {
+	return &FileClient{
+		metadataFile: metadataFile,
+		statsFile:    statsFile,
+	}
+}
+
+func (f *FileClient) RetrieveTaskMetadata(ctx context.Context) (*TaskMetadata, error) {
+	var out TaskMetadata
+	if err := readJSONFile(f.metadataFile, &out); err != nil {
+		return nil, err
+	}
+	out.SetTaskID()
+	out.SetPlatform()
+	return &out, nil
+}
+
+func (f *FileClient) RetrieveTaskStats(ctx context.Context) (map[string]*ContainerStats, error) {
+	out := make(map[string]*ContainerStats)
+	if err := readJSONFile(f.statsFile, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func readJSONFile(path string, out interface{}) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
@@ -14,14 +14,20 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/prometheus-community/ecs_exporter/ecscollector"
 	"github.com/prometheus-community/ecs_exporter/ecsmetadata"
+	"github.com/prometheus-community/ecs_exporter/otlpexporter"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -35,8 +41,42 @@ var (
 	addr string
 	shouldIgnoreExporterMetrics bool
 	customLabels  = make(map[string]string)
+	metadataFile string
+	statsFile string
+	refreshInterval time.Duration
+	otlpEndpoint string
+	otlpProtocol string
+	otlpInterval time.Duration
+	otlpHeaders  = make(map[string]string)
+	logLevel string
+	logFormat string
 )
 
+// newLogger builds the process-wide slog.Logger from the --log.level and
+// --log.format flags.
+func newLogger(level, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid --log.level %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: l}
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	case "logfmt":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("unsupported --log.format %q, want \"logfmt\" or \"json\"", format)
+	}
+}
+
+// fatal logs msg as an error and exits the process with a non-zero status.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
 
 func main() {
 	flag.StringVar(&addr, "addr", ":9779", "The address to listen on for HTTP requests.")
@@ -45,6 +85,32 @@ func main() {
 		false,
 		"Flag to stop the exporter should expose its own metrics. To enable it, just add `--ignore-exporter-metrics` to the command line.`")
 
+	flag.StringVar(&metadataFile, "metadata-file", "",
+		"[Optional] Path to a JSON file with task metadata (the shape returned by the \"/task\" metadata endpoint). When set together with --stats-file, the exporter reads from these files instead of querying the ECS metadata server. Useful for local development and testing.")
+	flag.StringVar(&statsFile, "stats-file", "",
+		"[Optional] Path to a JSON file with task stats (the shape returned by the \"/task/stats\" metadata endpoint). Must be set together with --metadata-file.")
+	flag.DurationVar(&refreshInterval, "refresh-interval", 15*time.Second,
+		"How often to refresh the cached task metadata and stats. Scrapes are served from this cache rather than querying the metadata server directly.")
+
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"[Optional] OTLP collector endpoint, e.g. `localhost:4317`. When set, metrics are additionally pushed here instead of only being exposed for Prometheus to scrape.")
+	flag.StringVar(&otlpProtocol, "otlp-protocol", "grpc",
+		"OTLP protocol to use when --otlp-endpoint is set. One of `grpc` or `http`.")
+	flag.DurationVar(&otlpInterval, "otlp-interval", 60*time.Second,
+		"How often to push metrics to --otlp-endpoint.")
+	flag.Func("otlp-headers", "[Optional] Headers sent with every OTLP export request. E.g. `--otlp-headers key1=value1,key2=value2`", func(otlpHeadersString string) error {
+		for _, pair := range strings.Split(otlpHeadersString, CUSTOM_LABEL_SEPARATOR) {
+			parts := strings.SplitN(pair, CUSTOM_LABEL_KEY_VALUE_SEPARATOR, 2)
+			if len(parts) == 2 {
+				otlpHeaders[parts[0]] = parts[1]
+			}
+		}
+		return nil
+	})
+
+	flag.StringVar(&logLevel, "log.level", "info", "Only log messages with the given severity or above. One of `debug`, `info`, `warn`, `error`.")
+	flag.StringVar(&logFormat, "log.format", "logfmt", "Output format of log messages. One of `logfmt` or `json`.")
+
 	flag.Func("custom-labels", "[Optional] Custom labels which will be added to all the metrics. E.g. `--custom-labels key1=value1,key2=value2`", func(customLabelsString string) error {
 		pairs := strings.Split(customLabelsString, CUSTOM_LABEL_SEPARATOR)
 
@@ -59,30 +125,87 @@ func main() {
 
 	flag.Parse()
 
-	client, err := ecsmetadata.NewClientFromEnvironment()
+	logger, err := newLogger(logLevel, logFormat)
 	if err != nil {
-		log.Fatalf("Error creating client: %v", err)
+		fatal("Error configuring logger", "error", err)
 	}
+	slog.SetDefault(logger)
+
+	var client ecsmetadata.MetadataSource
+	if metadataFile != "" || statsFile != "" {
+		if metadataFile == "" || statsFile == "" {
+			fatal("--metadata-file and --stats-file must be set together")
+		}
+		slog.Info("Reading task metadata from local files", "metadata_file", metadataFile, "stats_file", statsFile)
+		client = ecsmetadata.NewFileClient(metadataFile, statsFile)
+	} else {
+		c, err := ecsmetadata.NewClientFromEnvironment()
+		if err != nil {
+			fatal("Error creating client", "error", err)
+		}
+		client = c
+	}
+
+	collector := ecscollector.NewCollector(client, customLabels, refreshInterval)
 
 	if shouldIgnoreExporterMetrics {
-		log.Printf("Exporter metrics will not be exposed.")
+		slog.Info("Exporter metrics will not be exposed.")
 
 		// Create a new registry and exclude the default Go metrics
 		registry := prometheus.NewRegistry()
-		registry.MustRegister(ecscollector.NewCollector(client, customLabels))
+		registry.MustRegister(collector)
 		http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
 	} else {
-		log.Printf("Exporter metrics will be exposed.")
-		prometheus.MustRegister(ecscollector.NewCollector(client, customLabels))
+		slog.Info("Exporter metrics will be exposed.")
+		prometheus.MustRegister(collector)
 		http.Handle("/metrics", promhttp.Handler())
 	}
 
+	if otlpEndpoint != "" {
+		ctx := context.Background()
+		metadata, err := client.RetrieveTaskMetadata(ctx)
+		if err != nil {
+			fatal("Error retrieving task metadata for OTLP resource attributes", "error", err)
+		}
+
+		shutdown, err := otlpexporter.Start(ctx, collector, otlpexporter.Config{
+			Endpoint: otlpEndpoint,
+			Protocol: otlpProtocol,
+			Interval: otlpInterval,
+			Headers:  otlpHeaders,
+		}, otlpexporter.ResourceAttributes(metadata))
+		if err != nil {
+			fatal("Error starting OTLP exporter", "error", err)
+		}
+
+		slog.Info("Pushing metrics to OTLP endpoint", "endpoint", otlpEndpoint, "interval", otlpInterval)
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			sig := <-sigCh
+			slog.Info("Received signal, flushing OTLP exporter before exit", "signal", sig)
+			if err := shutdown(context.Background()); err != nil {
+				slog.Error("Error shutting down OTLP exporter", "error", err)
+			}
+			os.Exit(0)
+		}()
+	}
+
 	http.Handle("/", http.RedirectHandler("/metrics", http.StatusMovedPermanently))
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/-/healthy", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "ok")
+	})
+	http.HandleFunc("/-/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !collector.Ready() {
+			http.Error(w, "cached ECS metadata is unavailable or stale", http.StatusServiceUnavailable)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "ok")
 	})
 
-	log.Printf("Starting server at %q", addr)
-	log.Fatal(http.ListenAndServe(addr, nil))
+	slog.Info("Starting server", "addr", addr)
+	fatal("Server exited", "error", http.ListenAndServe(addr, nil))
 }
@@ -0,0 +1,200 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otlpexporter pushes the metrics produced by a prometheus.Collector
+// to an OTLP-compatible backend, as an alternative to exposing them on a
+// "/metrics" endpoint for Prometheus to scrape.
+package otlpexporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus-community/ecs_exporter/ecsmetadata"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Config configures the OTLP push exporter.
+type Config struct {
+	// Endpoint is the OTLP collector endpoint, e.g. "localhost:4317".
+	Endpoint string
+	// Protocol is either "grpc" or "http". Defaults to "grpc".
+	Protocol string
+	// Interval is how often metrics are pushed to Endpoint.
+	Interval time.Duration
+	// Headers are sent with every export request, e.g. for authentication.
+	Headers map[string]string
+}
+
+// Start begins periodically gathering metrics from collector and pushing
+// them to the OTLP endpoint described by cfg, tagged with resourceAttrs.
+// The returned shutdown func flushes and closes the exporter; callers
+// should invoke it on process exit.
+func Start(ctx context.Context, collector prometheus.Collector, cfg Config, resourceAttrs []attribute.KeyValue) (shutdown func(context.Context) error, err error) {
+	exp, err := newOTLPExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(resourceAttrs...))
+	if err != nil {
+		return nil, fmt.Errorf("building OTel resource: %w", err)
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exp, sdkmetric.WithInterval(cfg.Interval))
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(res),
+	)
+	meter := provider.Meter("github.com/prometheus-community/ecs_exporter")
+
+	if err := registerCollector(meter, collector); err != nil {
+		return nil, fmt.Errorf("registering OTel instruments: %w", err)
+	}
+
+	return provider.Shutdown, nil
+}
+
+func newOTLPExporter(ctx context.Context, cfg Config) (sdkmetric.Exporter, error) {
+	switch cfg.Protocol {
+	case "", "grpc":
+		return otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpoint(cfg.Endpoint),
+			otlpmetricgrpc.WithHeaders(cfg.Headers),
+			otlpmetricgrpc.WithInsecure(),
+		)
+	case "http":
+		return otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpoint(cfg.Endpoint),
+			otlpmetrichttp.WithHeaders(cfg.Headers),
+			otlpmetrichttp.WithInsecure(),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported --otlp-protocol %q, want \"grpc\" or \"http\"", cfg.Protocol)
+	}
+}
+
+// registerCollector registers collector into a private prometheus.Registry,
+// gathers it once to discover the metric families it exposes, and creates
+// one matching OTel observable instrument per family: a counter for
+// Prometheus counters, a gauge for everything else except histograms,
+// which get no instrument and are not pushed. This preserves the
+// monotonicity distinction the two APIs otherwise share. A single callback
+// then re-gathers the registry once per OTel collection cycle and feeds the
+// results into those instruments. This lets the OTLP push path and the
+// Prometheus scrape path share the same underlying Collect() call (and,
+// transitively, the same cached ECS metadata snapshot).
+func registerCollector(meter otelmetric.Meter, collector prometheus.Collector) error {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector); err != nil {
+		return fmt.Errorf("registering collector: %w", err)
+	}
+
+	mfs, err := registry.Gather()
+	if err != nil {
+		return fmt.Errorf("gathering metrics to discover instruments: %w", err)
+	}
+
+	instruments := make(map[string]otelmetric.Float64Observable, len(mfs))
+	observables := make([]otelmetric.Observable, 0, len(mfs))
+	for _, mf := range mfs {
+		name := mf.GetName()
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			counter, err := meter.Float64ObservableCounter(name)
+			if err != nil {
+				return fmt.Errorf("registering counter instrument for %q: %w", name, err)
+			}
+			instruments[name] = counter
+			observables = append(observables, counter)
+		case dto.MetricType_HISTOGRAM:
+			// The OTel SDK has no observable/async histogram instrument:
+			// an async callback can only report a single value per
+			// collection, not a distribution. Rather than mis-bridge this
+			// as a gauge reading either 0 or a meaningless one-off field,
+			// skip it; it's still exposed on the Prometheus scrape path.
+			continue
+		default:
+			gauge, err := meter.Float64ObservableGauge(name)
+			if err != nil {
+				return fmt.Errorf("registering gauge instrument for %q: %w", name, err)
+			}
+			instruments[name] = gauge
+			observables = append(observables, gauge)
+		}
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o otelmetric.Observer) error {
+		mfs, err := registry.Gather()
+		if err != nil {
+			return fmt.Errorf("gathering metrics: %w", err)
+		}
+
+		for _, mf := range mfs {
+			instrument, ok := instruments[mf.GetName()]
+			if !ok {
+				continue
+			}
+			for _, m := range mf.Metric {
+				o.ObserveFloat64(instrument, metricValue(m), otelmetric.WithAttributes(metricAttributes(m)...))
+			}
+		}
+		return nil
+	}, observables...)
+
+	return err
+}
+
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	default:
+		return 0
+	}
+}
+
+func metricAttributes(m *dto.Metric) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(m.Label))
+	for _, lp := range m.Label {
+		attrs = append(attrs, attribute.String(lp.GetName(), lp.GetValue()))
+	}
+	return attrs
+}
+
+// ResourceAttributes returns the OTel resource attributes describing the
+// ECS task the exporter is running in, following the aws.ecs.* and
+// cloud.* semantic conventions.
+func ResourceAttributes(metadata *ecsmetadata.TaskMetadata) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.CloudProviderAWS,
+		semconv.CloudPlatformAWSECS,
+		semconv.CloudAvailabilityZone(metadata.AvailabilityZone),
+		attribute.String("aws.ecs.cluster.arn", metadata.Cluster),
+		attribute.String("aws.ecs.task.arn", metadata.TaskARN),
+		semconv.AWSECSLaunchtypeKey.String(strings.ToLower(metadata.LaunchType)),
+	}
+}